@@ -0,0 +1,106 @@
+package backend
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+
+	"github.com/zserge/kv"
+	bolt "go.etcd.io/bbolt"
+)
+
+// Store implementation backed by a single BoltDB bucket. Unlike dirStore,
+// writes don't cost a file per key, which matters for workloads with many
+// small values. Each Set is still its own BoltDB transaction, synced to disk
+// when it commits.
+type boltStore struct {
+	wg     sync.WaitGroup
+	db     *bolt.DB
+	bucket []byte
+}
+
+// Creates a new store backed by a BoltDB database at the given path, with
+// all keys kept in the given bucket. The bucket is created if it doesn't
+// exist yet.
+func NewBoltStore(path string, bucket string) (kv.Store, error) {
+	db, err := bolt.Open(path, 0600, nil)
+	if err != nil {
+		return nil, err
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists([]byte(bucket))
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &boltStore{
+		db:     db,
+		bucket: []byte(bucket),
+	}, nil
+}
+
+func (store *boltStore) Get(key string, item kv.Item) kv.Item {
+	var found kv.Item
+	store.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket(store.bucket)
+		if data := b.Get([]byte(key)); data != nil {
+			if _, err := item.ReadFrom(bytes.NewReader(data)); err == nil {
+				found = item
+			}
+		}
+		return nil
+	})
+	return found
+}
+
+func (store *boltStore) Set(key string, item kv.Item) <-chan error {
+	c := make(chan error, 1)
+
+	store.wg.Add(1)
+	go func() {
+		defer store.wg.Done()
+		defer close(c)
+
+		err := store.db.Update(func(tx *bolt.Tx) error {
+			b := tx.Bucket(store.bucket)
+			if item == nil {
+				return b.Delete([]byte(key))
+			}
+			var buf bytes.Buffer
+			if _, err := item.WriteTo(&buf); err != nil {
+				return err
+			}
+			return b.Put([]byte(key), buf.Bytes())
+		})
+		if err != nil {
+			c <- err
+		}
+	}()
+	return c
+}
+
+func (store *boltStore) List(prefix string) []string {
+	keys := []string{}
+	store.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket(store.bucket)
+		return b.ForEach(func(k, v []byte) error {
+			if strings.HasPrefix(string(k), prefix) {
+				keys = append(keys, string(k))
+			}
+			return nil
+		})
+	})
+	return keys
+}
+
+func (store *boltStore) Flush() <-chan error {
+	c := make(chan error)
+	go func() {
+		store.wg.Wait()
+		store.db.Sync()
+		close(c)
+	}()
+	return c
+}
@@ -0,0 +1,65 @@
+package backend
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/zserge/kv"
+)
+
+func TestBoltStoreSet(t *testing.T) {
+	store, err := NewBoltStore(filepath.Join(t.TempDir(), "bolt.db"), "default")
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-store.Set("foo", &kv.ByteItem{Value: []byte("Hello")})
+	item := store.Get("foo", &kv.ByteItem{}).(*kv.ByteItem)
+	if string(item.Value) != "Hello" {
+		t.Error(item)
+	}
+	<-store.Set("foo", &kv.ByteItem{Value: []byte("World")})
+	item = store.Get("foo", &kv.ByteItem{}).(*kv.ByteItem)
+	if string(item.Value) != "World" {
+		t.Error(item)
+	}
+}
+
+func TestBoltStoreDel(t *testing.T) {
+	store, err := NewBoltStore(filepath.Join(t.TempDir(), "bolt.db"), "default")
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-store.Set("foo", &kv.ByteItem{Value: []byte("Hello")})
+	<-store.Set("foo", nil)
+	if item := store.Get("foo", &kv.ByteItem{}); item != nil {
+		t.Error(item)
+	}
+}
+
+func TestBoltStoreList(t *testing.T) {
+	store, err := NewBoltStore(filepath.Join(t.TempDir(), "bolt.db"), "default")
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.Set("foo", &kv.ByteItem{Value: []byte("Hello")})
+	store.Set("bar", &kv.ByteItem{Value: []byte("World")})
+	store.Set("baz", &kv.ByteItem{Value: []byte("!")})
+	<-store.Flush()
+	if keys := store.List(""); len(keys) != 3 {
+		t.Error(keys)
+	}
+	if keys := store.List("ba"); len(keys) != 2 {
+		t.Error(keys)
+	}
+}
+
+func TestBoltStoreFlush(t *testing.T) {
+	store, err := NewBoltStore(filepath.Join(t.TempDir(), "bolt.db"), "default")
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.Set("foo", &kv.ByteItem{Value: []byte("Hello")})
+	if err := <-store.Flush(); err != nil {
+		t.Error(err)
+	}
+}
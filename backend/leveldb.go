@@ -0,0 +1,94 @@
+package backend
+
+import (
+	"bytes"
+	"sync"
+
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/opt"
+	"github.com/syndtr/goleveldb/leveldb/util"
+	"github.com/zserge/kv"
+)
+
+// syncWrite makes every Put/Delete fsync its write to disk. goleveldb has no
+// way to sync already-written data after the fact the way bbolt's Sync or
+// dirStore's syscall.Sync do, so Flush relies on every write already having
+// landed durably by the time it completes.
+var syncWrite = &opt.WriteOptions{Sync: true}
+
+// Store implementation backed by a LevelDB database, useful for workloads
+// with a high write throughput where dirStore's one-file-per-key design
+// pays too much per-write overhead.
+type levelDBStore struct {
+	wg sync.WaitGroup
+	db *leveldb.DB
+}
+
+// Creates a new store backed by a LevelDB database at the given path. The
+// database is created if it doesn't exist yet.
+func NewLevelDBStore(path string) (kv.Store, error) {
+	db, err := leveldb.OpenFile(path, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &levelDBStore{db: db}, nil
+}
+
+func (store *levelDBStore) Get(key string, item kv.Item) kv.Item {
+	data, err := store.db.Get([]byte(key), nil)
+	if err != nil {
+		return nil
+	}
+	if _, err := item.ReadFrom(bytes.NewReader(data)); err != nil {
+		return nil
+	}
+	return item
+}
+
+func (store *levelDBStore) Set(key string, item kv.Item) <-chan error {
+	c := make(chan error, 1)
+
+	store.wg.Add(1)
+	go func() {
+		defer store.wg.Done()
+		defer close(c)
+
+		if item == nil {
+			if err := store.db.Delete([]byte(key), syncWrite); err != nil {
+				c <- err
+			}
+			return
+		}
+		var buf bytes.Buffer
+		if _, err := item.WriteTo(&buf); err != nil {
+			c <- err
+			return
+		}
+		if err := store.db.Put([]byte(key), buf.Bytes(), syncWrite); err != nil {
+			c <- err
+		}
+	}()
+	return c
+}
+
+func (store *levelDBStore) List(prefix string) []string {
+	keys := []string{}
+	iter := store.db.NewIterator(util.BytesPrefix([]byte(prefix)), nil)
+	defer iter.Release()
+	for iter.Next() {
+		keys = append(keys, string(iter.Key()))
+	}
+	return keys
+}
+
+// Flush waits for every in-flight Set to finish. Each of those writes is
+// already synced to disk (see syncWrite), so there is nothing left to force
+// once they've all landed.
+func (store *levelDBStore) Flush() <-chan error {
+	c := make(chan error)
+	go func() {
+		store.wg.Wait()
+		close(c)
+	}()
+	return c
+}
@@ -0,0 +1,83 @@
+// Package backend provides alternative Store implementations that can be
+// used in place of kv.NewStore, while still composing with kv.NewLRU and the
+// rest of the kv package.
+package backend
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/zserge/kv"
+)
+
+// Store implementation that keeps all items in memory. Useful for tests and
+// as a standalone cache tier when persistence is not required.
+type memStore struct {
+	mutex sync.RWMutex
+	data  map[string][]byte
+}
+
+// Creates a new in-memory store. Keys and values only live for the lifetime
+// of the process.
+func NewMemStore() kv.Store {
+	return &memStore{
+		data: make(map[string][]byte),
+	}
+}
+
+func (store *memStore) Get(key string, item kv.Item) kv.Item {
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+
+	if data, ok := store.data[key]; ok {
+		if _, err := item.ReadFrom(bytes.NewReader(data)); err == nil {
+			return item
+		}
+	}
+	return nil
+}
+
+func (store *memStore) Set(key string, item kv.Item) <-chan error {
+	c := make(chan error, 1)
+
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	if item == nil {
+		if _, ok := store.data[key]; !ok {
+			c <- fmt.Errorf("kv: key not found: %s", key)
+		} else {
+			delete(store.data, key)
+		}
+	} else {
+		var buf bytes.Buffer
+		if _, err := item.WriteTo(&buf); err != nil {
+			c <- err
+		} else {
+			store.data[key] = buf.Bytes()
+		}
+	}
+	close(c)
+	return c
+}
+
+func (store *memStore) List(prefix string) []string {
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+
+	keys := []string{}
+	for k := range store.data {
+		if strings.HasPrefix(k, prefix) {
+			keys = append(keys, k)
+		}
+	}
+	return keys
+}
+
+func (store *memStore) Flush() <-chan error {
+	c := make(chan error)
+	close(c)
+	return c
+}
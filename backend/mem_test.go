@@ -0,0 +1,59 @@
+package backend
+
+import (
+	"testing"
+
+	"github.com/zserge/kv"
+)
+
+func TestMemStoreSet(t *testing.T) {
+	store := NewMemStore()
+	<-store.Set("foo", &kv.ByteItem{Value: []byte("Hello")})
+	item := store.Get("foo", &kv.ByteItem{}).(*kv.ByteItem)
+	if string(item.Value) != "Hello" {
+		t.Error(item)
+	}
+	<-store.Set("foo", &kv.ByteItem{Value: []byte("World")})
+	item = store.Get("foo", &kv.ByteItem{}).(*kv.ByteItem)
+	if string(item.Value) != "World" {
+		t.Error(item)
+	}
+}
+
+func TestMemStoreDel(t *testing.T) {
+	store := NewMemStore()
+	<-store.Set("foo", &kv.ByteItem{Value: []byte("Hello")})
+	<-store.Set("foo", nil)
+	if item := store.Get("foo", &kv.ByteItem{}); item != nil {
+		t.Error(item)
+	}
+	if err := <-store.Set("missing key", nil); err == nil {
+		t.Error("missing key should return error on removal")
+	}
+}
+
+func TestMemStoreList(t *testing.T) {
+	store := NewMemStore()
+	store.Set("foo", &kv.ByteItem{Value: []byte("Hello")})
+	store.Set("bar", &kv.ByteItem{Value: []byte("World")})
+	store.Set("baz", &kv.ByteItem{Value: []byte("!")})
+	<-store.Flush()
+	if keys := store.List(""); len(keys) != 3 {
+		t.Error(keys)
+	}
+	if keys := store.List("ba"); len(keys) != 2 {
+		t.Error(keys)
+	}
+}
+
+func TestMemStoreWithLRU(t *testing.T) {
+	backend := NewMemStore()
+	store := kv.NewLRU(1, backend)
+	store.Set("foo", &kv.ByteItem{Value: []byte("Hello")})
+	store.Set("bar", &kv.ByteItem{Value: []byte("World")})
+	<-store.Flush()
+	item := backend.Get("foo", &kv.ByteItem{}).(*kv.ByteItem)
+	if string(item.Value) != "Hello" {
+		t.Error(item)
+	}
+}
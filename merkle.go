@@ -0,0 +1,273 @@
+package kv
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const manifestFileName = ".manifest"
+
+// MerkleStore is implemented by stores created with Options.ContentAddressable
+// set. It lets a caller get a succinct, tamper-evident summary of the whole
+// store (Root) and verify a single value against it (Prove), without having
+// to read every key.
+type MerkleStore interface {
+	Store
+	Root() [32]byte
+	Prove(key string) ([][]byte, error)
+}
+
+func hashLeaf(key string, valueHash [32]byte) [32]byte {
+	h := sha256.New()
+	h.Write([]byte{0x00})
+	h.Write([]byte(key))
+	h.Write(valueHash[:])
+	var out [32]byte
+	copy(out[:], h.Sum(nil))
+	return out
+}
+
+func hashNode(left, right [32]byte) [32]byte {
+	h := sha256.New()
+	h.Write([]byte{0x01})
+	h.Write(left[:])
+	h.Write(right[:])
+	var out [32]byte
+	copy(out[:], h.Sum(nil))
+	return out
+}
+
+// merkleTree is a bottom-up Merkle tree built over sorted (key, valueHash)
+// leaves, duplicating the last leaf of a level whenever its length is odd.
+type merkleTree struct {
+	levels [][][32]byte // levels[0] are the leaves, levels[len-1] is {root}
+}
+
+func buildMerkleTree(manifest map[string][32]byte) *merkleTree {
+	keys := sortedKeys(manifest)
+
+	leaves := make([][32]byte, len(keys))
+	for i, k := range keys {
+		leaves[i] = hashLeaf(k, manifest[k])
+	}
+
+	tree := &merkleTree{levels: [][][32]byte{leaves}}
+	if len(leaves) == 0 {
+		return tree
+	}
+
+	level := leaves
+	for len(level) > 1 {
+		if len(level)%2 == 1 {
+			level = append(level, level[len(level)-1])
+		}
+		next := make([][32]byte, len(level)/2)
+		for i := range next {
+			next[i] = hashNode(level[2*i], level[2*i+1])
+		}
+		tree.levels = append(tree.levels, next)
+		level = next
+	}
+	return tree
+}
+
+func (t *merkleTree) root() [32]byte {
+	top := t.levels[len(t.levels)-1]
+	if len(top) == 0 {
+		return [32]byte{}
+	}
+	return top[0]
+}
+
+// proof returns the sibling hash at every level on the path from the leaf at
+// index up to the root.
+func (t *merkleTree) proof(index int) [][]byte {
+	path := [][]byte{}
+	idx := index
+	for level := 0; level < len(t.levels)-1; level++ {
+		nodes := t.levels[level]
+		siblingIdx := idx ^ 1
+		if siblingIdx >= len(nodes) {
+			siblingIdx = idx // odd level: last leaf was duplicated
+		}
+		sibling := make([]byte, 32)
+		copy(sibling, nodes[siblingIdx][:])
+		path = append(path, sibling)
+		idx /= 2
+	}
+	return path
+}
+
+func sortedKeys(manifest map[string][32]byte) []string {
+	keys := make([]string, 0, len(manifest))
+	for k := range manifest {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Root returns the current Merkle root over this store's (key, valueHash)
+// pairs. Two content-addressable stores with the same root hold the same
+// data.
+func (store *dirStore) Root() [32]byte {
+	store.manifestMu.Lock()
+	defer store.manifestMu.Unlock()
+	return buildMerkleTree(store.manifest).root()
+}
+
+// Prove returns the sibling-hash path for key, leaf level first, which a
+// caller can combine with key, the value's hash and key's index among the
+// sorted keys to recompute and verify Root(). The index decides at each
+// level whether the sibling goes on the left (odd index) or the right.
+func (store *dirStore) Prove(key string) ([][]byte, error) {
+	store.manifestMu.Lock()
+	defer store.manifestMu.Unlock()
+
+	keys := sortedKeys(store.manifest)
+	index := sort.SearchStrings(keys, key)
+	if index >= len(keys) || keys[index] != key {
+		return nil, fmt.Errorf("kv: key not found: %s", key)
+	}
+
+	tree := buildMerkleTree(store.manifest)
+	return tree.proof(index), nil
+}
+
+func (store *dirStore) objectPath(hash [32]byte) string {
+	return filepath.Join(store.path, "objects", hex.EncodeToString(hash[:]))
+}
+
+// getCAS reads the value for key by way of the manifest, dereferencing it to
+// the content-addressed object file. Callers must hold store.mutex.
+func (store *dirStore) getCAS(key string, item Item) Item {
+	store.manifestMu.Lock()
+	hash, ok := store.manifest[key]
+	store.manifestMu.Unlock()
+	if !ok {
+		return nil
+	}
+
+	f, err := os.Open(store.objectPath(hash))
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+	if _, err := item.ReadFrom(f); err != nil {
+		return nil
+	}
+	return item
+}
+
+// setCAS writes item's encoding under sha256(value) - skipping the write
+// entirely if that object already exists, which is how identical values
+// shared across keys get deduplicated - and points key at it in the
+// manifest. A nil item removes key from the manifest; the object itself is
+// left on disk since other keys may still reference it.
+func (store *dirStore) setCAS(key string, item Item) error {
+	if item == nil {
+		store.manifestMu.Lock()
+		delete(store.manifest, key)
+		store.manifestMu.Unlock()
+		return nil
+	}
+
+	var buf bytes.Buffer
+	if _, err := item.WriteTo(&buf); err != nil {
+		return err
+	}
+	hash := sha256.Sum256(buf.Bytes())
+
+	objects := filepath.Join(store.path, "objects")
+	if err := os.MkdirAll(objects, store.opts.DirMode); err != nil {
+		return err
+	}
+	path := store.objectPath(hash)
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		tmp := path + ".tmp"
+		if err := os.WriteFile(tmp, buf.Bytes(), store.opts.FileMode); err != nil {
+			return err
+		}
+		if err := os.Rename(tmp, path); err != nil {
+			os.Remove(tmp)
+			return err
+		}
+	}
+
+	store.manifestMu.Lock()
+	store.manifest[key] = hash
+	store.manifestMu.Unlock()
+	return nil
+}
+
+func (store *dirStore) listCAS(prefix string) []string {
+	store.manifestMu.Lock()
+	defer store.manifestMu.Unlock()
+
+	keys := []string{}
+	for k := range store.manifest {
+		if strings.HasPrefix(k, prefix) {
+			keys = append(keys, k)
+		}
+	}
+	return keys
+}
+
+func (store *dirStore) manifestPath() string {
+	return filepath.Join(store.path, manifestFileName)
+}
+
+func (store *dirStore) loadManifest() {
+	store.manifest = make(map[string][32]byte)
+
+	data, err := os.ReadFile(store.manifestPath())
+	if err != nil {
+		return
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if line == "" {
+			continue
+		}
+		parts := strings.SplitN(line, "\t", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		raw, err := hex.DecodeString(parts[1])
+		if err != nil || len(raw) != 32 {
+			continue
+		}
+		var hash [32]byte
+		copy(hash[:], raw)
+		store.manifest[parts[0]] = hash
+	}
+}
+
+// persistManifest writes the key->hash mapping to disk. Callers must hold
+// store.manifestMu.
+func (store *dirStore) persistManifest() error {
+	if err := os.MkdirAll(store.path, store.opts.DirMode); err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	for _, k := range sortedKeys(store.manifest) {
+		hash := store.manifest[k]
+		fmt.Fprintf(&buf, "%s\t%s\n", k, hex.EncodeToString(hash[:]))
+	}
+
+	tmp := store.manifestPath() + ".tmp"
+	if err := os.WriteFile(tmp, buf.Bytes(), store.opts.FileMode); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, store.manifestPath()); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
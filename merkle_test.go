@@ -0,0 +1,124 @@
+package kv
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"os"
+	"testing"
+)
+
+const MerkleTestPath = "merkle-test"
+
+func newCASStore(t *testing.T) *dirStore {
+	t.Helper()
+	store := NewStoreWithOptions(MerkleTestPath, Options{ContentAddressable: true}).(*dirStore)
+	return store
+}
+
+func TestCASSetGet(t *testing.T) {
+	defer os.RemoveAll(MerkleTestPath)
+	store := newCASStore(t)
+
+	<-store.Set("foo", &ByteItem{[]byte("Hello")})
+	item := store.Get("foo", &ByteItem{}).(*ByteItem)
+	if string(item.Value) != "Hello" {
+		t.Error(item)
+	}
+	if keys := store.List(""); len(keys) != 1 || keys[0] != "foo" {
+		t.Error(keys)
+	}
+}
+
+func TestCASDedup(t *testing.T) {
+	defer os.RemoveAll(MerkleTestPath)
+	store := newCASStore(t)
+
+	<-store.Set("foo", &ByteItem{[]byte("same value")})
+	<-store.Set("bar", &ByteItem{[]byte("same value")})
+
+	entries, err := os.ReadDir(MerkleTestPath + "/objects")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Error("identical values should be deduplicated into one object:", entries)
+	}
+}
+
+func TestCASRootChangesWithData(t *testing.T) {
+	defer os.RemoveAll(MerkleTestPath)
+	store := newCASStore(t)
+
+	empty := store.Root()
+	<-store.Set("foo", &ByteItem{[]byte("Hello")})
+	withFoo := store.Root()
+	if empty == withFoo {
+		t.Error("root should change once a key is added")
+	}
+
+	<-store.Set("bar", &ByteItem{[]byte("World")})
+	withBoth := store.Root()
+	if withFoo == withBoth {
+		t.Error("root should change again once another key is added")
+	}
+}
+
+func TestCASProve(t *testing.T) {
+	defer os.RemoveAll(MerkleTestPath)
+	store := newCASStore(t)
+
+	<-store.Set("bar", &ByteItem{[]byte("World")})
+	<-store.Set("baz", &ByteItem{[]byte("!")})
+	<-store.Set("foo", &ByteItem{[]byte("Hello")})
+
+	proof, err := store.Prove("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var value bytes.Buffer
+	(&ByteItem{[]byte("Hello")}).WriteTo(&value)
+	valueHash := sha256.Sum256(value.Bytes())
+
+	// sorted keys are "bar", "baz", "foo" - "foo" is leaf index 2
+	hash := hashLeaf("foo", valueHash)
+	index := 2
+	for _, sibling := range proof {
+		var siblingHash [32]byte
+		copy(siblingHash[:], sibling)
+		if index%2 == 0 {
+			hash = hashNode(hash, siblingHash)
+		} else {
+			hash = hashNode(siblingHash, hash)
+		}
+		index /= 2
+	}
+
+	root := store.Root()
+	if hash != root {
+		t.Error("recomputed root from proof does not match store.Root()")
+	}
+}
+
+func TestCASProveMissingKey(t *testing.T) {
+	defer os.RemoveAll(MerkleTestPath)
+	store := newCASStore(t)
+	<-store.Set("foo", &ByteItem{[]byte("Hello")})
+
+	if _, err := store.Prove("missing"); err == nil {
+		t.Error("proving a missing key should return an error")
+	}
+}
+
+func TestCASManifestSurvivesReload(t *testing.T) {
+	defer os.RemoveAll(MerkleTestPath)
+	store := newCASStore(t)
+	<-store.Set("foo", &ByteItem{[]byte("Hello")})
+	<-store.Flush()
+
+	reopened := newCASStore(t)
+	item := reopened.Get("foo", &ByteItem{}).(*ByteItem)
+	if string(item.Value) != "Hello" {
+		t.Error(item)
+	}
+}
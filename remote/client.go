@@ -0,0 +1,156 @@
+package remote
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"sync"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+
+	"github.com/zserge/kv"
+)
+
+// storeClient wraps the dialed connection. dialErr is set instead of cc when
+// NewRemoteStore's grpc.NewClient call fails, so a bad address fails every
+// RPC with a clear error instead of a nil-pointer dereference in cc.Invoke.
+type storeClient struct {
+	cc      *grpc.ClientConn
+	dialErr error
+}
+
+func (c *storeClient) get(ctx context.Context, in *GetRequest) (*GetResponse, error) {
+	if c.dialErr != nil {
+		return nil, c.dialErr
+	}
+	out := new(GetResponse)
+	if err := c.cc.Invoke(ctx, "/kv.remote.Store/Get", in, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *storeClient) set(ctx context.Context, in *SetRequest) (*SetResponse, error) {
+	if c.dialErr != nil {
+		return nil, c.dialErr
+	}
+	out := new(SetResponse)
+	if err := c.cc.Invoke(ctx, "/kv.remote.Store/Set", in, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *storeClient) list(ctx context.Context, in *ListRequest) (*ListResponse, error) {
+	if c.dialErr != nil {
+		return nil, c.dialErr
+	}
+	out := new(ListResponse)
+	if err := c.cc.Invoke(ctx, "/kv.remote.Store/List", in, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *storeClient) flush(ctx context.Context, in *FlushRequest) (*FlushResponse, error) {
+	if c.dialErr != nil {
+		return nil, c.dialErr
+	}
+	out := new(FlushResponse)
+	if err := c.cc.Invoke(ctx, "/kv.remote.Store/Flush", in, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// remoteStore is a kv.Store backed by a Store served elsewhere with Serve.
+type remoteStore struct {
+	wg     sync.WaitGroup
+	client *storeClient
+}
+
+// NewRemoteStore dials addr and returns a Store that forwards every call to
+// the Store being served there with Serve. Dialing itself is lazy - as with
+// grpc.NewClient, connection errors (e.g. the server being down) only
+// surface on the first RPC. A malformed addr fails every RPC the same way,
+// rather than panicking on first use.
+func NewRemoteStore(addr string) kv.Store {
+	conn, err := grpc.NewClient(addr,
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithDefaultCallOptions(grpc.ForceCodec(gobCodec{})),
+	)
+	if err != nil {
+		return &remoteStore{client: &storeClient{dialErr: err}}
+	}
+	return &remoteStore{client: &storeClient{cc: conn}}
+}
+
+func (r *remoteStore) Get(key string, item kv.Item) kv.Item {
+	resp, err := r.client.get(context.Background(), &GetRequest{Key: key})
+	if err != nil || !resp.Found {
+		return nil
+	}
+	if _, err := item.ReadFrom(bytes.NewReader(resp.Data)); err != nil {
+		return nil
+	}
+	return item
+}
+
+// Set returns its error channel immediately; the RPC to the server happens
+// in the background and the channel closes once the server has acked it.
+func (r *remoteStore) Set(key string, item kv.Item) <-chan error {
+	c := make(chan error, 1)
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		defer close(c)
+
+		req := &SetRequest{Key: key}
+		if item == nil {
+			req.Delete = true
+		} else {
+			var buf bytes.Buffer
+			if _, err := item.WriteTo(&buf); err != nil {
+				c <- err
+				return
+			}
+			req.Data = buf.Bytes()
+		}
+
+		resp, err := r.client.set(context.Background(), req)
+		if err != nil {
+			c <- err
+			return
+		}
+		if resp.Err != "" {
+			c <- errors.New(resp.Err)
+		}
+	}()
+	return c
+}
+
+func (r *remoteStore) List(prefix string) []string {
+	resp, err := r.client.list(context.Background(), &ListRequest{Prefix: prefix})
+	if err != nil {
+		return []string{}
+	}
+	return resp.Keys
+}
+
+func (r *remoteStore) Flush() <-chan error {
+	c := make(chan error, 1)
+	go func() {
+		defer close(c)
+		r.wg.Wait()
+		resp, err := r.client.flush(context.Background(), &FlushRequest{})
+		if err != nil {
+			c <- err
+			return
+		}
+		if resp.Err != "" {
+			c <- errors.New(resp.Err)
+		}
+	}()
+	return c
+}
@@ -0,0 +1,27 @@
+package remote
+
+import (
+	"bytes"
+	"encoding/gob"
+)
+
+// gobCodec lets the Store service speak gRPC without a .proto file: it
+// frames messages the normal gRPC way but encodes them with encoding/gob
+// instead of protobuf.
+type gobCodec struct{}
+
+func (gobCodec) Marshal(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (gobCodec) Unmarshal(data []byte, v interface{}) error {
+	return gob.NewDecoder(bytes.NewReader(data)).Decode(v)
+}
+
+func (gobCodec) Name() string {
+	return "gob"
+}
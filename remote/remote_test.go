@@ -0,0 +1,104 @@
+package remote
+
+import (
+	"bytes"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/zserge/kv"
+)
+
+func TestNewRemoteStoreBadAddr(t *testing.T) {
+	store := NewRemoteStore("%zz")
+
+	if item := store.Get("foo", &kv.ByteItem{}); item != nil {
+		t.Error(item)
+	}
+	if err := <-store.Set("foo", &kv.ByteItem{Value: []byte("Hello")}); err == nil {
+		t.Error("Set against a malformed address should fail, not panic")
+	}
+	if keys := store.List(""); len(keys) != 0 {
+		t.Error(keys)
+	}
+	if err := <-store.Flush(); err == nil {
+		t.Error("Flush against a malformed address should fail, not panic")
+	}
+}
+
+func TestRemoteStore(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	backend := make(memStore)
+	go Serve(backend, listener)
+
+	store := NewRemoteStore(listener.Addr().String())
+
+	<-store.Set("foo", &kv.ByteItem{Value: []byte("Hello")})
+	item := store.Get("foo", &kv.ByteItem{}).(*kv.ByteItem)
+	if string(item.Value) != "Hello" {
+		t.Error(item)
+	}
+
+	store.Set("bar", &kv.ByteItem{Value: []byte("World")})
+	if err := <-store.Flush(); err != nil {
+		t.Error(err)
+	}
+
+	keys := store.List("")
+	if len(keys) != 2 {
+		t.Error(keys)
+	}
+
+	<-store.Set("foo", nil)
+	if item := store.Get("foo", &kv.ByteItem{}); item != nil {
+		t.Error(item)
+	}
+}
+
+// memStore is a tiny in-process kv.Store used only to exercise Serve/
+// NewRemoteStore against something that isn't on disk.
+type memStore map[string][]byte
+
+func (m memStore) Get(key string, item kv.Item) kv.Item {
+	data, ok := m[key]
+	if !ok {
+		return nil
+	}
+	if _, err := item.ReadFrom(bytes.NewReader(data)); err != nil {
+		return nil
+	}
+	return item
+}
+
+func (m memStore) Set(key string, item kv.Item) <-chan error {
+	c := make(chan error, 1)
+	if item == nil {
+		delete(m, key)
+	} else {
+		var buf bytes.Buffer
+		item.WriteTo(&buf)
+		m[key] = buf.Bytes()
+	}
+	close(c)
+	return c
+}
+
+func (m memStore) List(prefix string) []string {
+	keys := []string{}
+	for k := range m {
+		if strings.HasPrefix(k, prefix) {
+			keys = append(keys, k)
+		}
+	}
+	return keys
+}
+
+func (m memStore) Flush() <-chan error {
+	c := make(chan error)
+	close(c)
+	return c
+}
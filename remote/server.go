@@ -0,0 +1,60 @@
+// Package remote exposes a kv.Store over gRPC, so several processes can
+// share one backend (typically a dirStore) or use another process as a
+// cheap distributed cache tier via kv.NewLRU(size, remote.NewRemoteStore(addr)).
+package remote
+
+import (
+	"context"
+	"net"
+
+	"google.golang.org/grpc"
+
+	"github.com/zserge/kv"
+)
+
+// storeServer adapts a kv.Store to the storeServiceServer interface. Item
+// bodies cross the wire as raw bytes, via kv.ByteItem, so the server never
+// needs to know the concrete Item type the client is using.
+type storeServer struct {
+	store kv.Store
+}
+
+func (s *storeServer) get(ctx context.Context, req *GetRequest) (*GetResponse, error) {
+	item := s.store.Get(req.Key, &kv.ByteItem{})
+	if item == nil {
+		return &GetResponse{Found: false}, nil
+	}
+	return &GetResponse{Found: true, Data: item.(*kv.ByteItem).Value}, nil
+}
+
+func (s *storeServer) set(ctx context.Context, req *SetRequest) (*SetResponse, error) {
+	var item kv.Item
+	if !req.Delete {
+		item = &kv.ByteItem{Value: req.Data}
+	}
+	resp := &SetResponse{}
+	if err := <-s.store.Set(req.Key, item); err != nil {
+		resp.Err = err.Error()
+	}
+	return resp, nil
+}
+
+func (s *storeServer) list(ctx context.Context, req *ListRequest) (*ListResponse, error) {
+	return &ListResponse{Keys: s.store.List(req.Prefix)}, nil
+}
+
+func (s *storeServer) flush(ctx context.Context, req *FlushRequest) (*FlushResponse, error) {
+	resp := &FlushResponse{}
+	if err := <-s.store.Flush(); err != nil {
+		resp.Err = err.Error()
+	}
+	return resp, nil
+}
+
+// Serve exposes store over gRPC on listener. It blocks until the server
+// stops, the same way grpc.Server.Serve does.
+func Serve(store kv.Store, listener net.Listener) error {
+	server := grpc.NewServer(grpc.ForceServerCodec(gobCodec{}))
+	RegisterStoreServer(server, &storeServer{store: store})
+	return server.Serve(listener)
+}
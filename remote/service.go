@@ -0,0 +1,131 @@
+package remote
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+)
+
+// Messages for the Store service. There is no .proto file: gobCodec (see
+// codec.go) lets gRPC frame and transport these the normal way without a
+// protobuf schema.
+
+type GetRequest struct {
+	Key string
+}
+
+type GetResponse struct {
+	Found bool
+	Data  []byte
+}
+
+type SetRequest struct {
+	Key    string
+	Data   []byte
+	Delete bool
+}
+
+type SetResponse struct {
+	Err string
+}
+
+type ListRequest struct {
+	Prefix string
+}
+
+type ListResponse struct {
+	Keys []string
+}
+
+type FlushRequest struct{}
+
+type FlushResponse struct {
+	Err string
+}
+
+// storeServiceServer is implemented by storeServer (server.go) and invoked
+// through grpc.ServiceDesc below.
+type storeServiceServer interface {
+	get(context.Context, *GetRequest) (*GetResponse, error)
+	set(context.Context, *SetRequest) (*SetResponse, error)
+	list(context.Context, *ListRequest) (*ListResponse, error)
+	flush(context.Context, *FlushRequest) (*FlushResponse, error)
+}
+
+// RegisterStoreServer registers srv as the handler for the Store service on
+// s, the way a generated RegisterXxxServer function would.
+func RegisterStoreServer(s *grpc.Server, srv storeServiceServer) {
+	s.RegisterService(&storeServiceDesc, srv)
+}
+
+func storeGetHandler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(storeServiceServer).get(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{Server: srv, FullMethod: "/kv.remote.Store/Get"}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(storeServiceServer).get(ctx, req.(*GetRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func storeSetHandler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(storeServiceServer).set(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{Server: srv, FullMethod: "/kv.remote.Store/Set"}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(storeServiceServer).set(ctx, req.(*SetRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func storeListHandler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(storeServiceServer).list(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{Server: srv, FullMethod: "/kv.remote.Store/List"}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(storeServiceServer).list(ctx, req.(*ListRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func storeFlushHandler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(FlushRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(storeServiceServer).flush(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{Server: srv, FullMethod: "/kv.remote.Store/Flush"}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(storeServiceServer).flush(ctx, req.(*FlushRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+var storeServiceDesc = grpc.ServiceDesc{
+	ServiceName: "kv.remote.Store",
+	HandlerType: (*storeServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{MethodName: "Get", Handler: storeGetHandler},
+		{MethodName: "Set", Handler: storeSetHandler},
+		{MethodName: "List", Handler: storeListHandler},
+		{MethodName: "Flush", Handler: storeFlushHandler},
+	},
+	Streams: []grpc.StreamDesc{},
+}
@@ -1,6 +1,7 @@
 package kv
 
 import (
+	"bytes"
 	"container/list"
 	"io"
 	"net/url"
@@ -9,6 +10,7 @@ import (
 	"strings"
 	"sync"
 	"syscall"
+	"time"
 )
 
 // Item is something that can be put into a Store. Items should be able to
@@ -53,20 +55,81 @@ type Store interface {
 	Flush() <-chan error
 }
 
+// Closer is implemented by store types that may start a background
+// goroutine - an lru built with WithWriteBack, or a TTLStore built with
+// WithSweepInterval. Close stops that goroutine if one was started, and is a
+// harmless no-op otherwise; a type assertion to Closer does not by itself
+// tell you whether a goroutine is actually running.
+type Closer interface {
+	Close() error
+}
+
+// Batcher is implemented by stores that support applying several writes
+// together under one lock. See Batch.
+type Batcher interface {
+	Batch() *Batch
+}
+
 // Store implementation that keeps each item in its own file in the given
 // directory
 type dirStore struct {
 	mutex sync.RWMutex
 	wg    sync.WaitGroup
 	path  string
+	opts  Options
+
+	// manifest and manifestMu are only used when opts.ContentAddressable is
+	// set - see merkle.go.
+	manifestMu sync.Mutex
+	manifest   map[string][32]byte
+}
+
+// Options controls how a dirStore writes its files.
+type Options struct {
+	// Fsync makes every Set call f.Sync() the file before returning, so a
+	// write survives a crash as soon as its error channel closes.
+	Fsync bool
+	// Atomic makes every Set write to a temporary file and os.Rename it
+	// into place, so a crash mid-write can never leave a truncated value
+	// at the real key path.
+	Atomic bool
+	// DirMode is the mode used for directories created to hold keys.
+	// Defaults to 0700.
+	DirMode os.FileMode
+	// FileMode is the mode used for the files that hold values. Defaults
+	// to 0600.
+	FileMode os.FileMode
+	// ContentAddressable makes the store keep each value under
+	// sha256(value) on disk, with the key->hash mapping kept in a Merkle
+	// tree manifest. See merkle.go, MerkleStore.Root and MerkleStore.Prove.
+	// Fsync does not apply in this mode: value objects are written without
+	// a sync, and the manifest only reaches disk on Flush.
+	ContentAddressable bool
 }
 
 // Creates a new store from the given path. Keys are file names relative to the
 // path, values are file contents.
 func NewStore(path string) Store {
-	return &dirStore{
+	return NewStoreWithOptions(path, Options{Fsync: true, Atomic: true})
+}
+
+// Creates a new store like NewStore, but with explicit control over
+// durability and file permissions.
+func NewStoreWithOptions(path string, opts Options) Store {
+	if opts.DirMode == 0 {
+		opts.DirMode = 0700
+	}
+	if opts.FileMode == 0 {
+		opts.FileMode = 0600
+	}
+	store := &dirStore{
 		path: path,
+		opts: opts,
+	}
+	if opts.ContentAddressable {
+		store.loadManifest()
 	}
+	return store
 }
 
 func mkpath(root, s string) string {
@@ -81,6 +144,10 @@ func (store *dirStore) Get(key string, item Item) Item {
 	store.mutex.RLock()
 	defer store.mutex.RUnlock()
 
+	if store.opts.ContentAddressable {
+		return store.getCAS(key, item)
+	}
+
 	if f, err := os.Open(mkpath(store.path, key)); err == nil {
 		defer f.Close()
 		if _, err := item.ReadFrom(f); err == nil {
@@ -93,33 +160,131 @@ func (store *dirStore) Get(key string, item Item) Item {
 func (store *dirStore) Set(key string, item Item) <-chan error {
 	c := make(chan error, 1)
 
-	store.mutex.Lock()
-	defer store.mutex.Unlock()
-
 	store.wg.Add(1)
 	go func() {
 		defer store.wg.Done()
 		defer close(c)
-		s := mkpath(store.path, key)
-		if item == nil {
-			if err := os.Remove(s); err != nil {
-				c <- err
-			}
-		} else {
-			os.MkdirAll(filepath.Dir(s), 0700)
-
-			if f, err := os.OpenFile(s, os.O_WRONLY|os.O_CREATE, 0600); err != nil {
-				c <- err
-			} else {
-				// FIXME make this atomic (using file move/rename)
-				defer f.Close()
-				item.WriteTo(f)
-			}
+		store.mutex.Lock()
+		defer store.mutex.Unlock()
+		if err := store.write(key, item); err != nil {
+			c <- err
 		}
 	}()
 	return c
 }
 
+// write puts item at key on disk, honouring store.opts. Callers must hold
+// store.mutex.
+func (store *dirStore) write(key string, item Item) error {
+	if store.opts.ContentAddressable {
+		return store.setCAS(key, item)
+	}
+
+	s := mkpath(store.path, key)
+	if item == nil {
+		return os.Remove(s)
+	}
+
+	os.MkdirAll(filepath.Dir(s), store.opts.DirMode)
+
+	if !store.opts.Atomic {
+		f, err := os.OpenFile(s, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, store.opts.FileMode)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		if _, err := item.WriteTo(f); err != nil {
+			return err
+		}
+		if store.opts.Fsync {
+			return f.Sync()
+		}
+		return nil
+	}
+
+	tmp := s + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, store.opts.FileMode)
+	if err != nil {
+		return err
+	}
+	if _, err := item.WriteTo(f); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if store.opts.Fsync {
+		if err := f.Sync(); err != nil {
+			f.Close()
+			os.Remove(tmp)
+			return err
+		}
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if err := os.Rename(tmp, s); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
+// Batch collects Set/Delete operations to be applied together under a
+// single lock, with one syscall.Sync once they've all landed, so no other
+// Set on the store interleaves with a multi-key update. A failed operation
+// stops the batch; the operations before it stay applied and are not
+// rolled back.
+type Batch struct {
+	store *dirStore
+	ops   []batchOp
+}
+
+type batchOp struct {
+	key  string
+	item Item
+}
+
+// Batch starts a new batch of writes against this store.
+func (store *dirStore) Batch() *Batch {
+	return &Batch{store: store}
+}
+
+// Set adds a key/item write to the batch.
+func (b *Batch) Set(key string, item Item) *Batch {
+	b.ops = append(b.ops, batchOp{key: key, item: item})
+	return b
+}
+
+// Delete adds a key removal to the batch.
+func (b *Batch) Delete(key string) *Batch {
+	b.ops = append(b.ops, batchOp{key: key, item: nil})
+	return b
+}
+
+// Commit applies the queued operations in order under a single lock and
+// syncs the disk once at the end, returning a single error channel for the
+// whole batch. On the first failing operation it sends that error and
+// returns at once: later operations are skipped and no sync is done.
+func (b *Batch) Commit() <-chan error {
+	c := make(chan error, 1)
+	store := b.store
+
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	for _, op := range b.ops {
+		if err := store.write(op.key, op.item); err != nil {
+			c <- err
+			close(c)
+			return c
+		}
+	}
+	syscall.Sync()
+	close(c)
+	return c
+}
+
 func (store *dirStore) Flush() <-chan error {
 	// In disk store flush does not really return any errors because it can not
 	// know which write goroutines are now running so it can't collect their
@@ -128,6 +293,11 @@ func (store *dirStore) Flush() <-chan error {
 	c := make(chan error)
 	go func() {
 		store.wg.Wait()
+		if store.opts.ContentAddressable {
+			store.manifestMu.Lock()
+			store.persistManifest()
+			store.manifestMu.Unlock()
+		}
 		syscall.Sync()
 		close(c)
 	}()
@@ -137,6 +307,11 @@ func (store *dirStore) Flush() <-chan error {
 func (store *dirStore) List(prefix string) []string {
 	store.mutex.RLock()
 	defer store.mutex.RUnlock()
+
+	if store.opts.ContentAddressable {
+		return store.listCAS(prefix)
+	}
+
 	files := []string{}
 	if prefix == "" {
 		prefix = "/"
@@ -157,8 +332,14 @@ func (store *dirStore) List(prefix string) []string {
 }
 
 type lruItem struct {
-	K string
-	V Item
+	K       string
+	V       Item
+	Dirty   bool
+	Expires time.Time
+}
+
+func (item *lruItem) expired() bool {
+	return !item.Expires.IsZero() && time.Now().After(item.Expires)
 }
 
 type lru struct {
@@ -167,15 +348,89 @@ type lru struct {
 	mutex   sync.Mutex
 	size    int
 	backend Store
+
+	writeThrough bool
+	readThrough  bool
+
+	stop chan struct{}
+}
+
+// LRUOption configures the cache returned by NewLRU.
+type LRUOption func(*lru)
+
+// WithWriteThrough makes every Set also write the item to the backend
+// synchronously: the channel Set returns only closes once the backend write
+// (if any) has completed. This trades the latency of a backend round-trip
+// on every Set for never losing a recent write to a crash.
+func WithWriteThrough() LRUOption {
+	return func(store *lru) {
+		store.writeThrough = true
+	}
+}
+
+// WithWriteBack starts a background goroutine that flushes dirty entries to
+// the backend every flushInterval, so writes are eventually durable without
+// paying the backend latency on every Set. It has no effect together with
+// WithWriteThrough, since write-through entries are never dirty.
+//
+// The returned Store implements Closer; call Close to stop the goroutine
+// once the cache is no longer needed.
+func WithWriteBack(flushInterval time.Duration) LRUOption {
+	return func(store *lru) {
+		go store.writeBackLoop(flushInterval)
+	}
+}
+
+// WithReadThrough makes Get fault in missing keys from the backend and
+// populate the cache with them, the way a CPU cache services a miss from
+// main memory. This is the default behaviour when a backend is given.
+func WithReadThrough() LRUOption {
+	return func(store *lru) {
+		store.readThrough = true
+	}
 }
 
 // Returns LRU cache which is backed up to some other store.
-func NewLRU(size int, backend Store) Store {
-	return &lru{
-		l:       list.New(),
-		m:       make(map[string]*list.Element),
-		size:    size,
-		backend: backend,
+func NewLRU(size int, backend Store, opts ...LRUOption) Store {
+	store := &lru{
+		l:           list.New(),
+		m:           make(map[string]*list.Element),
+		size:        size,
+		backend:     backend,
+		readThrough: backend != nil,
+		stop:        make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(store)
+	}
+	return store
+}
+
+// Close stops the background goroutine started by WithWriteBack, if any. It
+// is a no-op otherwise. Close must only be called once.
+func (store *lru) Close() error {
+	close(store.stop)
+	return nil
+}
+
+func (store *lru) writeBackLoop(flushInterval time.Duration) {
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			store.mutex.Lock()
+			for _, el := range store.m {
+				pair := el.Value.(*lruItem)
+				if pair.Dirty && store.backend != nil {
+					<-store.backend.Set(pair.K, pair.V)
+					pair.Dirty = false
+				}
+			}
+			store.mutex.Unlock()
+		case <-store.stop:
+			return
+		}
 	}
 }
 
@@ -184,28 +439,51 @@ func (store *lru) Get(key string, item Item) Item {
 	defer store.mutex.Unlock()
 
 	if el, ok := store.m[key]; ok {
-		store.l.MoveToFront(el)
-		return el.Value.(*lruItem).V
-	} else if store.backend != nil {
+		cached := el.Value.(*lruItem)
+		if cached.expired() {
+			store.l.Remove(el)
+			delete(store.m, key)
+		} else if cached.V == nil {
+			// A key deleted while it was cached stays in the cache as a
+			// tombstone (V == nil) until evicted, so read-through doesn't
+			// resurrect it from the backend.
+			return nil
+		} else {
+			store.l.MoveToFront(el)
+			// Round-trip through the item's own encoding so the caller gets
+			// an isolated copy instead of a pointer into the cache's state.
+			var buf bytes.Buffer
+			if _, err := cached.V.WriteTo(&buf); err != nil {
+				return nil
+			}
+			if _, err := item.ReadFrom(&buf); err != nil {
+				return nil
+			}
+			return item
+		}
+	}
+	if store.readThrough && store.backend != nil {
 		if item := store.backend.Get(key, item); item != nil {
-			<-store.put(key, item)
+			if c := store.put(key, item, false, time.Time{}); c != nil {
+				<-c
+			}
 			return item
 		}
 	}
 	return nil
 }
 
-func (store *lru) put(key string, item Item) (c <-chan error) {
+func (store *lru) put(key string, item Item, dirty bool, expires time.Time) (c <-chan error) {
 	if len(store.m) < store.size {
-		store.m[key] = store.l.PushFront(&lruItem{key, item})
+		store.m[key] = store.l.PushFront(&lruItem{K: key, V: item, Dirty: dirty, Expires: expires})
 	} else {
 		el := store.l.Back()
 		value := el.Value.(*lruItem)
-		if store.backend != nil {
+		if value.Dirty && !value.expired() && store.backend != nil {
 			c = store.backend.Set(value.K, value.V)
 		}
 		delete(store.m, value.K)
-		el.Value = &lruItem{key, item}
+		el.Value = &lruItem{K: key, V: item, Dirty: dirty, Expires: expires}
 		store.l.MoveToFront(el)
 		store.m[key] = el
 	}
@@ -213,20 +491,41 @@ func (store *lru) put(key string, item Item) (c <-chan error) {
 }
 
 func (store *lru) Set(key string, item Item) <-chan error {
+	return store.setWithTTL(key, item, 0)
+}
+
+// SetWithTTL behaves like Set, but the cached entry expires and is treated
+// as a miss after ttl elapses, independently of whatever TTL (if any) the
+// backend itself applies.
+func (store *lru) SetWithTTL(key string, item Item, ttl time.Duration) <-chan error {
+	return store.setWithTTL(key, item, ttl)
+}
+
+func (store *lru) setWithTTL(key string, item Item, ttl time.Duration) <-chan error {
 	store.mutex.Lock()
 	defer store.mutex.Unlock()
 
-	c := make(chan error)
-	close(c)
+	dirty := !store.writeThrough
+	expires := time.Time{}
+	if ttl > 0 {
+		expires = time.Now().Add(ttl)
+	}
 
 	if el, ok := store.m[key]; ok {
-		el.Value = &lruItem{key, item}
+		el.Value = &lruItem{K: key, V: item, Dirty: dirty, Expires: expires}
 		store.l.MoveToFront(el)
 	} else if item != nil {
-		if c := store.put(key, item); c != nil {
+		if c := store.put(key, item, dirty, expires); c != nil {
 			return c
 		}
 	}
+
+	if store.writeThrough && store.backend != nil {
+		return store.backend.Set(key, item)
+	}
+
+	c := make(chan error)
+	close(c)
 	return c
 }
 
@@ -234,8 +533,8 @@ func (store *lru) List(prefix string) []string {
 	store.mutex.Lock()
 	defer store.mutex.Unlock()
 	keys := []string{}
-	for k, _ := range store.m {
-		if strings.HasPrefix(k, prefix) {
+	for k, el := range store.m {
+		if strings.HasPrefix(k, prefix) && !el.Value.(*lruItem).expired() {
 			keys = append(keys, k)
 		}
 	}
@@ -251,6 +550,7 @@ func (store *lru) Flush() <-chan error {
 			for _, v := range store.m {
 				pair := v.Value.(*lruItem)
 				store.backend.Set(pair.K, pair.V)
+				pair.Dirty = false
 			}
 			if err, ok := <-store.backend.Flush(); ok {
 				c <- err
@@ -1,8 +1,10 @@
 package kv
 
 import (
+	"io"
 	"os"
 	"testing"
+	"time"
 )
 
 const StoreTestPath = "store-test"
@@ -99,6 +101,102 @@ func TestStoreFlush(t *testing.T) {
 	<-store.Flush()
 }
 
+func TestStoreAtomicSetNoTmpLeftover(t *testing.T) {
+	store := NewStore(StoreTestPath)
+	defer os.RemoveAll(StoreTestPath)
+	<-store.Set("foo", &ByteItem{[]byte("Hello")})
+	if _, err := os.Stat(StoreTestPath + "/foo.tmp"); !os.IsNotExist(err) {
+		t.Error("temporary file should not survive a successful Set:", err)
+	}
+	item := store.Get("foo", &ByteItem{}).(*ByteItem)
+	if string(item.Value) != "Hello" {
+		t.Error(item)
+	}
+}
+
+func TestStoreWithOptionsNoFsync(t *testing.T) {
+	store := NewStoreWithOptions(StoreTestPath, Options{Atomic: true})
+	defer os.RemoveAll(StoreTestPath)
+	<-store.Set("foo", &ByteItem{[]byte("Hello")})
+	item := store.Get("foo", &ByteItem{}).(*ByteItem)
+	if string(item.Value) != "Hello" {
+		t.Error(item)
+	}
+}
+
+func TestStoreBatch(t *testing.T) {
+	store := NewStore(StoreTestPath).(*dirStore)
+	defer os.RemoveAll(StoreTestPath)
+
+	<-store.Set("foo", &ByteItem{[]byte("old")})
+	err := <-store.Batch().
+		Set("foo", &ByteItem{[]byte("Hello")}).
+		Set("bar", &ByteItem{[]byte("World")}).
+		Delete("baz").
+		Commit()
+	if err == nil {
+		t.Error("deleting a missing key should surface an error from the batch")
+	}
+
+	item := store.Get("foo", &ByteItem{}).(*ByteItem)
+	if string(item.Value) != "Hello" {
+		t.Error(item)
+	}
+	item = store.Get("bar", &ByteItem{}).(*ByteItem)
+	if string(item.Value) != "World" {
+		t.Error(item)
+	}
+}
+
+// slowItem wraps a ByteItem whose WriteTo blocks for a while before writing,
+// so a test can hold store.mutex for a known duration.
+type slowItem struct {
+	ByteItem
+	delay time.Duration
+}
+
+func (s *slowItem) WriteTo(w io.Writer) (int64, error) {
+	time.Sleep(s.delay)
+	return s.ByteItem.WriteTo(w)
+}
+
+func TestStoreSetExcludedByBatchCommit(t *testing.T) {
+	store := NewStore(StoreTestPath).(*dirStore)
+	defer os.RemoveAll(StoreTestPath)
+
+	commitDone := make(chan time.Time, 1)
+	go func() {
+		<-store.Batch().Set("foo", &slowItem{ByteItem{[]byte("batched")}, 50 * time.Millisecond}).Commit()
+		commitDone <- time.Now()
+	}()
+	time.Sleep(10 * time.Millisecond)
+
+	setDone := make(chan time.Time, 1)
+	go func() {
+		<-store.Set("bar", &ByteItem{[]byte("World")})
+		setDone <- time.Now()
+	}()
+
+	commitAt := <-commitDone
+	setAt := <-setDone
+	if setAt.Before(commitAt) {
+		t.Error("Set on a different key completed before a concurrent Batch.Commit released store.mutex")
+	}
+}
+
+func TestStoreSetOverwriteShorterValueTruncates(t *testing.T) {
+	store := NewStore(StoreTestPath)
+	defer os.RemoveAll(StoreTestPath)
+
+	<-store.Set("foo", &ByteItem{[]byte("a very long value indeed")})
+	<-store.Set("foo", &ByteItem{[]byte("short")})
+
+	item := store.Get("foo", &ByteItem{}).(*ByteItem)
+	if string(item.Value) != "short" {
+		t.Error(item)
+	}
+}
+
 func TestLRUWithoutBackend(t *testing.T) {
 	store := NewLRU(2, nil)
 	store.Set("foo", &ByteItem{[]byte("Hello")})
@@ -133,6 +231,15 @@ func TestLRUWithoutBackend(t *testing.T) {
 	}
 }
 
+func TestLRUGetAfterDelete(t *testing.T) {
+	store := NewLRU(2, nil)
+	store.Set("foo", &ByteItem{[]byte("Hello")})
+	<-store.Set("foo", nil)
+	if item := store.Get("foo", &ByteItem{}); item != nil {
+		t.Error(item)
+	}
+}
+
 func TestLRUWithBackend(t *testing.T) {
 	dir := NewStore(StoreTestPath)
 	defer os.RemoveAll(StoreTestPath)
@@ -186,6 +293,64 @@ func TestLRUWithBackend(t *testing.T) {
 	}
 }
 
+func TestLRUGetIsolation(t *testing.T) {
+	store := NewLRU(2, nil)
+	store.Set("foo", &ByteItem{[]byte("Hello")})
+
+	item := store.Get("foo", &ByteItem{}).(*ByteItem)
+	item.Value[0] = 'h'
+
+	item = store.Get("foo", &ByteItem{}).(*ByteItem)
+	if string(item.Value) != "Hello" {
+		t.Error("cached item should not be mutated by the caller:", string(item.Value))
+	}
+}
+
+func TestLRUWriteThrough(t *testing.T) {
+	dir := NewStore(StoreTestPath)
+	defer os.RemoveAll(StoreTestPath)
+	store := NewLRU(2, dir, WithWriteThrough())
+
+	<-store.Set("foo", &ByteItem{[]byte("Hello")})
+	if item := dir.Get("foo", &ByteItem{}); item == nil {
+		t.Error("write-through Set should reach the backend immediately")
+	}
+}
+
+func TestLRUWriteBack(t *testing.T) {
+	dir := NewStore(StoreTestPath)
+	defer os.RemoveAll(StoreTestPath)
+	store := NewLRU(2, dir, WithWriteBack(10*time.Millisecond))
+
+	store.Set("foo", &ByteItem{[]byte("Hello")})
+	if item := dir.Get("foo", &ByteItem{}); item != nil {
+		t.Error("write-back Set should not reach the backend synchronously")
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	if item := dir.Get("foo", &ByteItem{}); item == nil {
+		t.Error("write-back flush should have reached the backend by now")
+	}
+}
+
+func TestLRUWriteBackClose(t *testing.T) {
+	dir := NewStore(StoreTestPath)
+	defer os.RemoveAll(StoreTestPath)
+	store := NewLRU(2, dir, WithWriteBack(10*time.Millisecond))
+
+	closer, ok := store.(Closer)
+	if !ok {
+		t.Fatal("store started with WithWriteBack should implement Closer")
+	}
+	closer.Close()
+
+	store.Set("foo", &ByteItem{[]byte("Hello")})
+	time.Sleep(50 * time.Millisecond)
+	if item := dir.Get("foo", &ByteItem{}); item != nil {
+		t.Error("write-back loop should not flush after Close")
+	}
+}
+
 func TestItemJSON(t *testing.T) {
 	type jsonItem struct {
 		Foo string `json:"foo"`
@@ -220,3 +385,47 @@ func TestItemGob(t *testing.T) {
 		t.Error(b)
 	}
 }
+
+func TestItemGzip(t *testing.T) {
+	defer os.RemoveAll(StoreTestPath)
+	store := NewStore(StoreTestPath)
+	<-store.Set("foo", &GzipItem{Inner: &ByteItem{Value: []byte("Hello")}})
+
+	item := store.Get("foo", &GzipItem{Inner: &ByteItem{}}).(*GzipItem)
+	if string(item.Inner.(*ByteItem).Value) != "Hello" {
+		t.Error(item.Inner)
+	}
+}
+
+func TestItemSnappy(t *testing.T) {
+	defer os.RemoveAll(StoreTestPath)
+	store := NewStore(StoreTestPath)
+	<-store.Set("foo", &SnappyItem{Inner: &ByteItem{Value: []byte("Hello")}})
+
+	item := store.Get("foo", &SnappyItem{Inner: &ByteItem{}}).(*SnappyItem)
+	if string(item.Inner.(*ByteItem).Value) != "Hello" {
+		t.Error(item.Inner)
+	}
+}
+
+func TestItemEncrypted(t *testing.T) {
+	defer os.RemoveAll(StoreTestPath)
+	store := NewStore(StoreTestPath)
+	key := []byte("0123456789abcdef")
+
+	<-store.Set("foo", &EncryptedItem{Inner: &ByteItem{Value: []byte("Hello")}, Key: key})
+
+	raw := store.Get("foo", &ByteItem{}).(*ByteItem)
+	if string(raw.Value) == "Hello" {
+		t.Error("value should not be readable without decrypting it first")
+	}
+
+	item := store.Get("foo", &EncryptedItem{Inner: &ByteItem{}, Key: key}).(*EncryptedItem)
+	if string(item.Inner.(*ByteItem).Value) != "Hello" {
+		t.Error(item.Inner)
+	}
+
+	if item := store.Get("foo", &EncryptedItem{Inner: &ByteItem{}, Key: []byte("fedcba9876543210")}); item != nil {
+		t.Error("decrypting with the wrong key should fail")
+	}
+}
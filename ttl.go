@@ -0,0 +1,181 @@
+package kv
+
+import (
+	"encoding/binary"
+	"io"
+	"time"
+)
+
+// ttlItem wraps another Item with an expiry, so the expiry travels alongside
+// the value wherever the backend persists it.
+type ttlItem struct {
+	Expires time.Time
+	Inner   Item
+}
+
+// nanos is 0 for "never expires" - Expires.UnixNano() otherwise, which is
+// never legitimately 0 since that would mean an expiry at the Unix epoch.
+func (t *ttlItem) WriteTo(w io.Writer) (int64, error) {
+	var nanos int64
+	if !t.Expires.IsZero() {
+		nanos = t.Expires.UnixNano()
+	}
+	if err := binary.Write(w, binary.BigEndian, nanos); err != nil {
+		return 0, err
+	}
+	n, err := t.Inner.WriteTo(w)
+	return n + 8, err
+}
+
+func (t *ttlItem) ReadFrom(r io.Reader) (int64, error) {
+	var nanos int64
+	if err := binary.Read(r, binary.BigEndian, &nanos); err != nil {
+		return 0, err
+	}
+	if nanos == 0 {
+		t.Expires = time.Time{}
+	} else {
+		t.Expires = time.Unix(0, nanos)
+	}
+	n, err := t.Inner.ReadFrom(r)
+	return n + 8, err
+}
+
+func (t *ttlItem) expired() bool {
+	return !t.Expires.IsZero() && time.Now().After(t.Expires)
+}
+
+// Store wrapper that persists an expiry alongside each item. Keys whose TTL
+// has elapsed no longer show up in Get or List, and are reaped from the
+// backend lazily as they're encountered, or eagerly by a background sweeper
+// if one was started with WithSweepInterval.
+type ttlStore struct {
+	backend  Store
+	onExpire func(key string)
+	stop     chan struct{}
+}
+
+// TTLOption configures a store created with NewTTL.
+type TTLOption func(*ttlStore)
+
+// WithExpirationCallback registers a function that is called with the key
+// whenever an expired entry is reaped, whether that happens lazily on
+// Get/List or from the background sweeper.
+func WithExpirationCallback(f func(key string)) TTLOption {
+	return func(store *ttlStore) {
+		store.onExpire = f
+	}
+}
+
+// WithSweepInterval starts a background goroutine that removes expired keys
+// from the backend every interval, instead of only reaping them the next
+// time they are looked up.
+//
+// The returned TTLStore implements Closer; call Close to stop the goroutine
+// once the store is no longer needed.
+func WithSweepInterval(interval time.Duration) TTLOption {
+	return func(store *ttlStore) {
+		go store.sweepLoop(interval)
+	}
+}
+
+// TTLStore is a Store that can also give individual items a limited
+// lifetime.
+type TTLStore interface {
+	Store
+	SetWithTTL(key string, item Item, ttl time.Duration) <-chan error
+}
+
+// Wraps a backend store so that items can be given a TTL via SetWithTTL.
+// Plain Set never expires, same as the backend on its own.
+func NewTTL(backend Store, opts ...TTLOption) TTLStore {
+	store := &ttlStore{backend: backend, stop: make(chan struct{})}
+	for _, opt := range opts {
+		opt(store)
+	}
+	return store
+}
+
+// Close stops the background goroutine started by WithSweepInterval, if
+// any. It is a no-op otherwise. Close must only be called once.
+func (store *ttlStore) Close() error {
+	close(store.stop)
+	return nil
+}
+
+func (store *ttlStore) Get(key string, item Item) Item {
+	wrapper := &ttlItem{Inner: item}
+	if store.backend.Get(key, wrapper) == nil {
+		return nil
+	}
+	if wrapper.expired() {
+		store.reap(key)
+		return nil
+	}
+	return item
+}
+
+func (store *ttlStore) Set(key string, item Item) <-chan error {
+	if item == nil {
+		return store.backend.Set(key, nil)
+	}
+	return store.SetWithTTL(key, item, 0)
+}
+
+// SetWithTTL stores item so that it (and its key) stop being visible through
+// Get and List once ttl elapses. A ttl of zero means the item never expires.
+func (store *ttlStore) SetWithTTL(key string, item Item, ttl time.Duration) <-chan error {
+	if item == nil {
+		return store.backend.Set(key, nil)
+	}
+	expires := time.Time{}
+	if ttl > 0 {
+		expires = time.Now().Add(ttl)
+	}
+	return store.backend.Set(key, &ttlItem{Expires: expires, Inner: item})
+}
+
+func (store *ttlStore) List(prefix string) []string {
+	keys := []string{}
+	for _, key := range store.backend.List(prefix) {
+		wrapper := &ttlItem{Inner: &ByteItem{}}
+		if store.backend.Get(key, wrapper) == nil {
+			continue
+		}
+		if wrapper.expired() {
+			store.reap(key)
+			continue
+		}
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+func (store *ttlStore) Flush() <-chan error {
+	return store.backend.Flush()
+}
+
+func (store *ttlStore) reap(key string) {
+	<-store.backend.Set(key, nil)
+	if store.onExpire != nil {
+		store.onExpire(key)
+	}
+}
+
+func (store *ttlStore) sweepLoop(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			for _, key := range store.backend.List("") {
+				wrapper := &ttlItem{Inner: &ByteItem{}}
+				if store.backend.Get(key, wrapper) != nil && wrapper.expired() {
+					store.reap(key)
+				}
+			}
+		case <-store.stop:
+			return
+		}
+	}
+}
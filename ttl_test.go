@@ -0,0 +1,122 @@
+package kv
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+const TTLTestPath = "ttl-test"
+
+func TestTTLExpiry(t *testing.T) {
+	dir := NewStore(TTLTestPath)
+	defer os.RemoveAll(TTLTestPath)
+	store := NewTTL(dir)
+
+	<-store.SetWithTTL("foo", &ByteItem{[]byte("Hello")}, 10*time.Millisecond)
+	if item := store.Get("foo", &ByteItem{}); item == nil {
+		t.Error("item should still be visible before it expires")
+	}
+
+	time.Sleep(20 * time.Millisecond)
+	if item := store.Get("foo", &ByteItem{}); item != nil {
+		t.Error("item should no longer be visible after it expires")
+	}
+	if item := dir.Get("foo", &ByteItem{}); item != nil {
+		t.Error("expired item should have been reaped from the backend")
+	}
+}
+
+func TestTTLNoExpiry(t *testing.T) {
+	dir := NewStore(TTLTestPath)
+	defer os.RemoveAll(TTLTestPath)
+	store := NewTTL(dir)
+
+	<-store.Set("foo", &ByteItem{[]byte("Hello")})
+	time.Sleep(10 * time.Millisecond)
+	item := store.Get("foo", &ByteItem{}).(*ByteItem)
+	if string(item.Value) != "Hello" {
+		t.Error(item)
+	}
+}
+
+func TestTTLList(t *testing.T) {
+	dir := NewStore(TTLTestPath)
+	defer os.RemoveAll(TTLTestPath)
+	store := NewTTL(dir)
+
+	<-store.Set("foo", &ByteItem{[]byte("Hello")})
+	<-store.SetWithTTL("bar", &ByteItem{[]byte("World")}, 10*time.Millisecond)
+	time.Sleep(20 * time.Millisecond)
+
+	keys := store.List("")
+	if len(keys) != 1 || keys[0] != "foo" {
+		t.Error(keys)
+	}
+}
+
+func TestTTLExpirationCallback(t *testing.T) {
+	dir := NewStore(TTLTestPath)
+	defer os.RemoveAll(TTLTestPath)
+
+	expired := make(chan string, 1)
+	store := NewTTL(dir, WithExpirationCallback(func(key string) {
+		expired <- key
+	}))
+
+	<-store.SetWithTTL("foo", &ByteItem{[]byte("Hello")}, 10*time.Millisecond)
+	time.Sleep(20 * time.Millisecond)
+	store.Get("foo", &ByteItem{})
+
+	select {
+	case key := <-expired:
+		if key != "foo" {
+			t.Error(key)
+		}
+	case <-time.After(time.Second):
+		t.Error("expiration callback was not invoked")
+	}
+}
+
+func TestTTLSweepInterval(t *testing.T) {
+	dir := NewStore(TTLTestPath)
+	defer os.RemoveAll(TTLTestPath)
+	store := NewTTL(dir, WithSweepInterval(10*time.Millisecond))
+	defer store.(Closer).Close()
+
+	<-store.SetWithTTL("foo", &ByteItem{[]byte("Hello")}, 10*time.Millisecond)
+	time.Sleep(30 * time.Millisecond)
+	if item := dir.Get("foo", &ByteItem{}); item != nil {
+		t.Error("sweeper should have reaped the expired key from the backend")
+	}
+}
+
+func TestTTLSweepIntervalClose(t *testing.T) {
+	dir := NewStore(TTLTestPath)
+	defer os.RemoveAll(TTLTestPath)
+	store := NewTTL(dir, WithSweepInterval(10*time.Millisecond))
+
+	closer, ok := store.(Closer)
+	if !ok {
+		t.Fatal("store started with WithSweepInterval should implement Closer")
+	}
+	closer.Close()
+
+	<-store.SetWithTTL("foo", &ByteItem{[]byte("Hello")}, 10*time.Millisecond)
+	time.Sleep(30 * time.Millisecond)
+	if item := dir.Get("foo", &ByteItem{}); item == nil {
+		t.Error("sweeper should not run after Close")
+	}
+}
+
+func TestLRUSetWithTTL(t *testing.T) {
+	store := NewLRU(2, nil).(*lru)
+	<-store.SetWithTTL("foo", &ByteItem{[]byte("Hello")}, 10*time.Millisecond)
+	if item := store.Get("foo", &ByteItem{}); item == nil {
+		t.Error("item should still be visible before it expires")
+	}
+	time.Sleep(20 * time.Millisecond)
+	if item := store.Get("foo", &ByteItem{}); item != nil {
+		t.Error("cache entry should no longer be visible after it expires")
+	}
+}